@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// bearerAuth wraps next with static bearer-token authentication. If token
+// is empty, next is returned unwrapped.
+func bearerAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, prefix) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		got := strings.TrimPrefix(header, prefix)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serverTLSConfig builds a *tls.Config for the SD server from cfg, or nil
+// if TLS isn't enabled. When ClientCAFile is set, client certificates are
+// verified if presented (tls.VerifyClientCertIfGiven) rather than required
+// at the handshake, so unauthenticated callers like /healthz and /readyz
+// probes can still complete a TLS connection; requireClientCert enforces
+// the requirement per-route instead.
+func serverTLSConfig(cfg ServerTLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+	if cfg.ClientCAFile == "" {
+		return &tls.Config{}, nil
+	}
+
+	caCert, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading server.tls.client_ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("server.tls.client_ca_file: no certificates found in %s", cfg.ClientCAFile)
+	}
+
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
+
+// requireClientCert wraps next with the mTLS requirement deferred from the
+// handshake: requests must present a verified client certificate. If
+// mTLSEnabled is false, next is returned unwrapped.
+func requireClientCert(mTLSEnabled bool, next http.Handler) http.Handler {
+	if !mTLSEnabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "Client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
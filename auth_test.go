@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+// testCACert is a throwaway self-signed certificate used only to exercise
+// serverTLSConfig's PEM parsing; it is not used to terminate a real
+// connection anywhere in these tests.
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIDBTCCAe2gAwIBAgIUGkbnRBzxsz/VqYLD7EnCJ2+70IowDQYJKoZIhvcNAQEL
+BQAwEjEQMA4GA1UEAwwHdGVzdC1jYTAeFw0yNjA3MjYwNzU4MDBaFw0yNjA3Mjcw
+NzU4MDBaMBIxEDAOBgNVBAMMB3Rlc3QtY2EwggEiMA0GCSqGSIb3DQEBAQUAA4IB
+DwAwggEKAoIBAQCqiXag2dUq1SdbH0oPX7KXocCQNCvVS0l7drOoP79nwXCa219s
+XntCastZYWsisyr0BBYqKtfkTue++/Zz7qmWBuegqnCOyO2sRN3WjtpEzbKSEjxX
+OKMEHS34uydu3vYTM4YLP7Vu+tes598OS3Mt3ZlMHgVpBPIpIfUyffM05q6SGibZ
+yoSKyzqk3ar+QGOdpSgxvT0E+DP1c9UFWgUwXk48406cJ12HcIhgS2P5KsVAi6KL
+s8Iqruw17Gz+iFyQyKh0XKGPmEQfaZhgb+Nu3BHKkwNcfV744xll7myt3OJp5fli
+jstW9zK1ZvCml8oXvW8/kf0vzf4PXU/zQC3XAgMBAAGjUzBRMB0GA1UdDgQWBBRj
+W9FZtAitADY+Pqf5LBQFDwseyzAfBgNVHSMEGDAWgBRjW9FZtAitADY+Pqf5LBQF
+DwseyzAPBgNVHRMBAf8EBTADAQH/MA0GCSqGSIb3DQEBCwUAA4IBAQCAykYD6tyE
+FCryQg/JpxFHB+GbCqG6LcW4W68Gc5jUhgQTxscy+aXN6DYmw91M3eu3uyk89col
+z7XkF5EFW14WlHOpLr7dE0tCcYii981zHzkEcosiXRY/XeXtTswxWAYB3FjPDKbb
+0w9KP8d98l8a8U5Yazq6UjCsJ0WyP/w50Eytd7OtOpGIERAJuIoLQiBC3G5wOAOb
+nbmF6qG56SgxiZs8hdaqR1uesSSVE4EWHdA4Sd4L+35aIo9QphubYOIQXMg4Y552
+HgXnp55aBPlvVOAWkgV5o+owxskvg8uALGjB4w7cz5GBp/VNup/QrS+BZD7BCBfb
+fJOERWlmiCNn
+-----END CERTIFICATE-----
+`
+
+func TestBearerAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"no token configured, no header required", "", "", http.StatusOK},
+		{"correct token", "s3cr3t", "Bearer s3cr3t", http.StatusOK},
+		{"missing header", "s3cr3t", "", http.StatusUnauthorized},
+		{"wrong token", "s3cr3t", "Bearer wrong", http.StatusUnauthorized},
+		{"missing bearer prefix", "s3cr3t", "s3cr3t", http.StatusUnauthorized},
+		{"empty bearer value", "s3cr3t", "Bearer ", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sd/job", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			bearerAuth(tt.token, ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("disabled passes through without TLS state", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sd/job", nil)
+		rec := httptest.NewRecorder()
+
+		requireClientCert(false, ok).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("enabled rejects requests without a peer certificate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sd/job", nil)
+		rec := httptest.NewRecorder()
+
+		requireClientCert(true, ok).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	t.Run("no cert file means TLS disabled", func(t *testing.T) {
+		cfg, err := serverTLSConfig(ServerTLSConfig{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg != nil {
+			t.Fatalf("expected nil tls.Config, got %+v", cfg)
+		}
+	})
+
+	t.Run("cert without client CA enables plain TLS", func(t *testing.T) {
+		cfg, err := serverTLSConfig(ServerTLSConfig{
+			TLSConfig: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg == nil {
+			t.Fatal("expected non-nil tls.Config")
+		}
+		if cfg.ClientCAs != nil {
+			t.Fatalf("expected no client CA pool, got %+v", cfg.ClientCAs)
+		}
+	})
+
+	t.Run("valid client CA file enables verify-if-given mTLS", func(t *testing.T) {
+		dir := t.TempDir()
+		caPath := filepath.Join(dir, "ca.pem")
+		writeFile(t, caPath, testCACert)
+
+		cfg, err := serverTLSConfig(ServerTLSConfig{
+			TLSConfig:    TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"},
+			ClientCAFile: caPath,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.ClientCAs == nil {
+			t.Fatal("expected a populated client CA pool")
+		}
+		if cfg.ClientAuth != tls.VerifyClientCertIfGiven {
+			t.Fatalf("ClientAuth = %v, want VerifyClientCertIfGiven", cfg.ClientAuth)
+		}
+	})
+
+	t.Run("missing client CA file errors", func(t *testing.T) {
+		_, err := serverTLSConfig(ServerTLSConfig{
+			TLSConfig:    TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"},
+			ClientCAFile: "/does/not/exist.pem",
+		})
+		if err == nil {
+			t.Fatal("expected an error for a missing client CA file")
+		}
+	})
+
+	t.Run("client CA file with no valid certs errors", func(t *testing.T) {
+		dir := t.TempDir()
+		caPath := filepath.Join(dir, "ca.pem")
+		writeFile(t, caPath, "not a certificate")
+
+		_, err := serverTLSConfig(ServerTLSConfig{
+			TLSConfig:    TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"},
+			ClientCAFile: caPath,
+		})
+		if err == nil {
+			t.Fatal("expected an error for a CA file with no parseable certificates")
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the top-level YAML configuration for nautobot_http_sd, modelled
+// after Prometheus's own config style: a handful of global sections plus a
+// list of per-job definitions.
+type Config struct {
+	Nautobot NautobotConfig `yaml:"nautobot"`
+	Server   ServerConfig   `yaml:"server"`
+	Jobs     []JobConfig    `yaml:"jobs"`
+}
+
+// NautobotConfig holds the connection details for the Nautobot GraphQL API.
+type NautobotConfig struct {
+	URL   string    `yaml:"url"`
+	Token string    `yaml:"token"`
+	TLS   TLSConfig `yaml:"tls"`
+}
+
+// ServerConfig holds the listener configuration for the SD HTTP server.
+type ServerConfig struct {
+	ListenAddr string          `yaml:"listen_addr"`
+	TLS        ServerTLSConfig `yaml:"tls"`
+	Auth       AuthConfig      `yaml:"auth"`
+}
+
+// TLSConfig is shared between the Nautobot client and the SD server.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// ServerTLSConfig is the SD server's TLS config: a server certificate plus
+// an optional client CA bundle to require and verify client certificates
+// (mTLS) against.
+type ServerTLSConfig struct {
+	TLSConfig    `yaml:",inline"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// AuthConfig configures static bearer-token authentication on the SD
+// server. BearerToken and BearerTokenFile are mutually exclusive; leaving
+// both unset disables authentication.
+type AuthConfig struct {
+	BearerToken     string `yaml:"bearer_token"`
+	BearerTokenFile string `yaml:"bearer_token_file"`
+
+	// ResolvedToken is BearerToken or the contents of BearerTokenFile,
+	// populated by LoadConfig. Not part of the YAML schema.
+	ResolvedToken string `yaml:"-"`
+}
+
+// JobConfig describes a single http_sd endpoint: what to query, how often,
+// where to serve it, and how to turn each device into Prometheus labels.
+type JobConfig struct {
+	Name            string            `yaml:"name"`
+	QueryFile       string            `yaml:"query_file"`
+	Endpoint        string            `yaml:"endpoint"`
+	Labels          map[string]string `yaml:"labels"`
+	DeviceFilter    string            `yaml:"device_filter"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval"`
+
+	// PreferIPv6 selects primary_ip6 over primary_ip4 when both are
+	// present; otherwise whichever address is set is used.
+	PreferIPv6 bool `yaml:"prefer_ipv6"`
+
+	// Port is appended to every target's address unless overridden for
+	// that device's role in PortOverrides. Zero means no port is added.
+	Port int `yaml:"port"`
+
+	// PortOverrides maps a device role name to a scrape port, e.g.
+	// {"switch": 9116} for an snmp_exporter job that also scrapes
+	// node_exporter-equipped hosts on a different default port.
+	PortOverrides map[string]int `yaml:"port_overrides"`
+}
+
+const defaultListenAddr = ":6645"
+
+// LoadConfig reads and parses the YAML config file at path. Environment
+// variables referenced as ${VAR} or $VAR anywhere in the file are expanded
+// before parsing, so secrets like the Nautobot token can be kept out of the
+// file itself.
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	expanded := os.Expand(string(raw), func(name string) string {
+		return os.Getenv(name)
+	})
+
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	cfg.applyDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	token, err := cfg.Server.Auth.resolveToken()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.Auth.ResolvedToken = token
+
+	return &cfg, nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.Server.ListenAddr == "" {
+		c.Server.ListenAddr = defaultListenAddr
+	}
+	for i := range c.Jobs {
+		if c.Jobs[i].Endpoint == "" {
+			c.Jobs[i].Endpoint = "/sd/" + c.Jobs[i].Name
+		}
+		if c.Jobs[i].RefreshInterval == 0 {
+			c.Jobs[i].RefreshInterval = defaultRefreshInterval
+		}
+	}
+}
+
+// Validate checks that the config is complete and internally consistent,
+// returning a descriptive error for the first problem found so operators
+// don't have to guess why the server refused to start.
+func (c *Config) Validate() error {
+	if c.Nautobot.URL == "" {
+		return fmt.Errorf("nautobot.url must be set")
+	}
+	if c.Nautobot.Token == "" {
+		return fmt.Errorf("nautobot.token must be set")
+	}
+	if err := c.Nautobot.TLS.Validate("nautobot.tls"); err != nil {
+		return err
+	}
+	if err := c.Server.TLS.Validate("server.tls"); err != nil {
+		return err
+	}
+	if c.Server.Auth.BearerToken != "" && c.Server.Auth.BearerTokenFile != "" {
+		return fmt.Errorf("server.auth: bearer_token and bearer_token_file are mutually exclusive")
+	}
+
+	if len(c.Jobs) == 0 {
+		return fmt.Errorf("at least one job must be configured")
+	}
+
+	seenNames := make(map[string]bool, len(c.Jobs))
+	seenEndpoints := make(map[string]bool, len(c.Jobs))
+	for i, job := range c.Jobs {
+		if job.Name == "" {
+			return fmt.Errorf("jobs[%d].name must be set", i)
+		}
+		if job.QueryFile == "" {
+			return fmt.Errorf("jobs[%d] (%s): query_file must be set", i, job.Name)
+		}
+		if seenNames[job.Name] {
+			return fmt.Errorf("duplicate job name %q", job.Name)
+		}
+		seenNames[job.Name] = true
+
+		if seenEndpoints[job.Endpoint] {
+			return fmt.Errorf("duplicate job endpoint %q (job %s)", job.Endpoint, job.Name)
+		}
+		seenEndpoints[job.Endpoint] = true
+	}
+
+	return nil
+}
+
+// Validate reports an error if exactly one of CertFile/KeyFile is set,
+// since ListenAndServeTLS requires both.
+func (t TLSConfig) Validate(field string) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("%s: cert_file and key_file must both be set, or both left empty", field)
+	}
+	return nil
+}
+
+// Validate extends TLSConfig.Validate with the mTLS-specific requirement
+// that a client CA bundle only makes sense alongside a server certificate.
+func (t ServerTLSConfig) Validate(field string) error {
+	if err := t.TLSConfig.Validate(field); err != nil {
+		return err
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		return fmt.Errorf("%s: client_ca_file requires cert_file/key_file to also be set", field)
+	}
+	return nil
+}
+
+// resolveToken returns the configured bearer token, reading it from
+// BearerTokenFile if that's how it was set. An empty result disables
+// bearer-token authentication.
+func (a AuthConfig) resolveToken() (string, error) {
+	if a.BearerTokenFile == "" {
+		return a.BearerToken, nil
+	}
+	data, err := os.ReadFile(a.BearerTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("reading server.auth.bearer_token_file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("server.auth.bearer_token_file %q is empty", a.BearerTokenFile)
+	}
+	return token, nil
+}
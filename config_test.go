@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const validJobsBlock = `
+jobs:
+  - name: routers
+    query_file: query.graphql
+`
+
+func TestLoadConfigValidatesAndExpandsEnv(t *testing.T) {
+	t.Run("expands ${VAR} references before parsing", func(t *testing.T) {
+		t.Setenv("NAUTOBOT_TOKEN", "s3cr3t-from-env")
+
+		path := writeConfig(t, `
+nautobot:
+  url: https://nautobot.example.com/graphql/
+  token: ${NAUTOBOT_TOKEN}
+`+validJobsBlock)
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Nautobot.Token != "s3cr3t-from-env" {
+			t.Fatalf("token = %q, want %q", cfg.Nautobot.Token, "s3cr3t-from-env")
+		}
+	})
+
+	t.Run("applies endpoint and refresh interval defaults", func(t *testing.T) {
+		path := writeConfig(t, `
+nautobot:
+  url: https://nautobot.example.com/graphql/
+  token: s3cr3t
+`+validJobsBlock)
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Server.ListenAddr != defaultListenAddr {
+			t.Fatalf("listen_addr = %q, want %q", cfg.Server.ListenAddr, defaultListenAddr)
+		}
+		if cfg.Jobs[0].Endpoint != "/sd/routers" {
+			t.Fatalf("endpoint = %q, want %q", cfg.Jobs[0].Endpoint, "/sd/routers")
+		}
+		if cfg.Jobs[0].RefreshInterval != defaultRefreshInterval {
+			t.Fatalf("refresh_interval = %v, want %v", cfg.Jobs[0].RefreshInterval, defaultRefreshInterval)
+		}
+	})
+
+	t.Run("missing file errors", func(t *testing.T) {
+		if _, err := LoadConfig("/does/not/exist.yaml"); err == nil {
+			t.Fatal("expected an error for a missing config file")
+		}
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	validNautobot := NautobotConfig{URL: "https://nautobot.example.com/graphql/", Token: "s3cr3t"}
+	validJob := JobConfig{Name: "routers", QueryFile: "query.graphql", Endpoint: "/sd/routers"}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{
+			name:    "missing nautobot.url",
+			cfg:     Config{Nautobot: NautobotConfig{Token: "s3cr3t"}, Jobs: []JobConfig{validJob}},
+			wantErr: "nautobot.url must be set",
+		},
+		{
+			name:    "missing nautobot.token",
+			cfg:     Config{Nautobot: NautobotConfig{URL: validNautobot.URL}, Jobs: []JobConfig{validJob}},
+			wantErr: "nautobot.token must be set",
+		},
+		{
+			name: "nautobot cert_file without key_file",
+			cfg: Config{
+				Nautobot: NautobotConfig{URL: validNautobot.URL, Token: validNautobot.Token, TLS: TLSConfig{CertFile: "tls.crt"}},
+				Jobs:     []JobConfig{validJob},
+			},
+			wantErr: "nautobot.tls: cert_file and key_file must both be set, or both left empty",
+		},
+		{
+			name: "server client_ca_file without cert_file",
+			cfg: Config{
+				Nautobot: validNautobot,
+				Server:   ServerConfig{TLS: ServerTLSConfig{ClientCAFile: "ca.pem"}},
+				Jobs:     []JobConfig{validJob},
+			},
+			wantErr: "server.tls: client_ca_file requires cert_file/key_file to also be set",
+		},
+		{
+			name:    "zero jobs",
+			cfg:     Config{Nautobot: validNautobot},
+			wantErr: "at least one job must be configured",
+		},
+		{
+			name: "duplicate job name",
+			cfg: Config{
+				Nautobot: validNautobot,
+				Jobs: []JobConfig{
+					validJob,
+					{Name: "routers", QueryFile: "other.graphql", Endpoint: "/sd/other"},
+				},
+			},
+			wantErr: `duplicate job name "routers"`,
+		},
+		{
+			name: "duplicate job endpoint",
+			cfg: Config{
+				Nautobot: validNautobot,
+				Jobs: []JobConfig{
+					validJob,
+					{Name: "switches", QueryFile: "other.graphql", Endpoint: "/sd/routers"},
+				},
+			},
+			wantErr: `duplicate job endpoint "/sd/routers" (job switches)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if err.Error() != tt.wantErr {
+				t.Fatalf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthConfigResolveToken(t *testing.T) {
+	t.Run("inline token", func(t *testing.T) {
+		cfg := AuthConfig{BearerToken: "s3cr3t"}
+		got, err := cfg.resolveToken()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "s3cr3t" {
+			t.Fatalf("got %q, want %q", got, "s3cr3t")
+		}
+	})
+
+	t.Run("neither set disables auth", func(t *testing.T) {
+		got, err := AuthConfig{}.resolveToken()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "" {
+			t.Fatalf("got %q, want empty string", got)
+		}
+	})
+
+	t.Run("token from file, whitespace trimmed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(path, []byte("  s3cr3t\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := AuthConfig{BearerTokenFile: path}
+		got, err := cfg.resolveToken()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "s3cr3t" {
+			t.Fatalf("got %q, want %q", got, "s3cr3t")
+		}
+	})
+
+	t.Run("empty file is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(path, []byte("   \n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := AuthConfig{BearerTokenFile: path}
+		if _, err := cfg.resolveToken(); err == nil {
+			t.Fatal("expected an error for an empty token file")
+		}
+	})
+
+	t.Run("missing file errors", func(t *testing.T) {
+		cfg := AuthConfig{BearerTokenFile: "/does/not/exist"}
+		if _, err := cfg.resolveToken(); err == nil {
+			t.Fatal("expected an error for a missing token file")
+		}
+	})
+}
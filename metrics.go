@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the self-observability instruments exposed on /metrics.
+// Everything is labeled by job so a single process serving several
+// http_sd endpoints still yields per-job signal.
+type Metrics struct {
+	refreshTotal         *prometheus.CounterVec
+	refreshFailuresTotal *prometheus.CounterVec
+	refreshDuration      *prometheus.HistogramVec
+	lastRefreshTimestamp *prometheus.GaugeVec
+	targets              *prometheus.GaugeVec
+
+	// httpRequestsTotal, httpRequestDuration and httpRequestsInFlight are
+	// standard promhttp request instrumentation for the SD endpoints
+	// themselves, labeled by job so each http_sd route is broken out
+	// individually alongside the Nautobot refresh metrics above.
+	httpRequestsTotal    *prometheus.CounterVec
+	httpRequestDuration  *prometheus.HistogramVec
+	httpRequestsInFlight *prometheus.GaugeVec
+}
+
+// NewMetrics registers the nautobot_sd_* instruments on reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+	return &Metrics{
+		refreshTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "nautobot_sd_refresh_total",
+			Help: "Total number of Nautobot refresh attempts, per job.",
+		}, []string{"job"}),
+		refreshFailuresTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "nautobot_sd_refresh_failures_total",
+			Help: "Total number of failed Nautobot refresh attempts, per job.",
+		}, []string{"job"}),
+		refreshDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nautobot_sd_refresh_duration_seconds",
+			Help:    "Duration of Nautobot refresh attempts, per job.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"job"}),
+		lastRefreshTimestamp: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "nautobot_sd_last_refresh_timestamp_seconds",
+			Help: "Unix timestamp of the last successful Nautobot refresh, per job.",
+		}, []string{"job"}),
+		targets: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "nautobot_sd_targets",
+			Help: "Number of targets currently being served, per job.",
+		}, []string{"job"}),
+		httpRequestsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "nautobot_sd_http_requests_total",
+			Help: "Total number of HTTP requests to an http_sd endpoint, per job.",
+		}, []string{"job", "code", "method"}),
+		httpRequestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nautobot_sd_http_request_duration_seconds",
+			Help:    "Duration of HTTP requests to an http_sd endpoint, per job.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"job", "code", "method"}),
+		httpRequestsInFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "nautobot_sd_http_requests_in_flight",
+			Help: "Number of in-flight HTTP requests to an http_sd endpoint, per job.",
+		}, []string{"job"}),
+	}
+}
+
+// instrumentSD wraps an http_sd job's handler with standard promhttp
+// request-count, duration and in-flight instrumentation, curried with the
+// job's name so the per-route signal lines up with the refresh metrics
+// above.
+func instrumentSD(m *Metrics, job string, next http.Handler) http.Handler {
+	counter := m.httpRequestsTotal.MustCurryWith(prometheus.Labels{"job": job})
+	duration := m.httpRequestDuration.MustCurryWith(prometheus.Labels{"job": job})
+	inFlight := m.httpRequestsInFlight.WithLabelValues(job)
+
+	return promhttp.InstrumentHandlerInFlight(inFlight,
+		promhttp.InstrumentHandlerDuration(duration,
+			promhttp.InstrumentHandlerCounter(counter, next)))
+}
+
+// healthz always reports 200 once the process is up and serving.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyzHandler reports 200 once every job has completed at least one
+// successful Nautobot fetch, and 503 otherwise.
+func readyzHandler(refreshers []*jobRefresher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, jr := range refreshers {
+			if !jr.Ready() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// metricsHandler returns the promhttp handler for reg.
+func metricsHandler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentSD(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := instrumentSD(metrics, "routers", ok)
+
+	req := httptest.NewRequest(http.MethodGet, "/sd/routers", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	got := testutil.ToFloat64(metrics.httpRequestsTotal.WithLabelValues("routers", "200", "get"))
+	if got != 1 {
+		t.Fatalf("nautobot_sd_http_requests_total{job=routers} = %v, want 1", got)
+	}
+}
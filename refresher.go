@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultRefreshInterval is used for jobs that don't set refresh_interval.
+const defaultRefreshInterval = 30 * time.Second
+
+// snapshot is a single job's rendered target list along with the metadata
+// needed to answer conditional requests cheaply.
+type snapshot struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	targetCount  int
+}
+
+// jobRefresher periodically re-fetches a single job's targets from Nautobot
+// and makes the latest successfully rendered snapshot available for
+// concurrent reads from its HTTP handler. A failed refresh leaves the
+// previous snapshot in place and increments the refresh_failures_total
+// metric.
+type jobRefresher struct {
+	job      JobConfig
+	nautobot NautobotConfig
+	client   *http.Client
+	metrics  *Metrics
+	current  atomic.Value // holds *snapshot
+	ready    atomic.Bool  // set once the first refresh succeeds
+}
+
+func newJobRefresher(nautobot NautobotConfig, job JobConfig, client *http.Client, metrics *Metrics) *jobRefresher {
+	r := &jobRefresher{job: job, nautobot: nautobot, client: client, metrics: metrics}
+	r.current.Store(newSnapshot([]byte("[]")))
+	return r
+}
+
+func newSnapshot(body []byte) *snapshot {
+	sum := sha256.Sum256(body)
+
+	var targets []json.RawMessage
+	_ = json.Unmarshal(body, &targets)
+
+	return &snapshot{
+		body:         body,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: time.Now(),
+		targetCount:  len(targets),
+	}
+}
+
+// Run performs an initial fetch and then refreshes on the job's configured
+// interval until ctx is cancelled.
+func (r *jobRefresher) Run(ctx context.Context) {
+	r.refresh(ctx)
+
+	ticker := time.NewTicker(r.job.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+func (r *jobRefresher) refresh(ctx context.Context) {
+	r.metrics.refreshTotal.WithLabelValues(r.job.Name).Inc()
+	timer := prometheus.NewTimer(r.metrics.refreshDuration.WithLabelValues(r.job.Name))
+	jsonData, err := fetchTargets(ctx, r.client, r.nautobot, r.job)
+	timer.ObserveDuration()
+
+	if err != nil {
+		r.metrics.refreshFailuresTotal.WithLabelValues(r.job.Name).Inc()
+		log.Printf("job %s: error refreshing targets, serving stale data: %v", r.job.Name, err)
+		return
+	}
+
+	next := newSnapshot(jsonData)
+	r.metrics.lastRefreshTimestamp.WithLabelValues(r.job.Name).Set(float64(next.lastModified.Unix()))
+	r.metrics.targets.WithLabelValues(r.job.Name).Set(float64(next.targetCount))
+	r.ready.Store(true)
+
+	if prev := r.Snapshot(); prev.etag == next.etag {
+		// Content hasn't actually changed; don't bump Last-Modified.
+		return
+	}
+	r.current.Store(next)
+}
+
+// Snapshot returns the most recently rendered snapshot for this job.
+func (r *jobRefresher) Snapshot() *snapshot {
+	return r.current.Load().(*snapshot)
+}
+
+// Ready reports whether this job has completed at least one successful
+// Nautobot fetch.
+func (r *jobRefresher) Ready() bool {
+	return r.ready.Load()
+}
+
+// ServeHTTP serves the job's current snapshot, honoring If-None-Match and
+// If-Modified-Since with a 304 when the client's cached copy is current.
+func (r *jobRefresher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	snap := r.Snapshot()
+
+	w.Header().Set("ETag", snap.etag)
+	w.Header().Set("Last-Modified", snap.lastModified.UTC().Format(http.TimeFormat))
+
+	if notModified(req, snap) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(snap.body)
+}
+
+func notModified(req *http.Request, snap *snapshot) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return inm == snap.etag
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err == nil && !snap.lastModified.Truncate(time.Second).After(since) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	snap := &snapshot{etag: `"abc123"`, lastModified: now}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		ifModSince  string
+		want        bool
+	}{
+		{
+			name:        "matching etag",
+			ifNoneMatch: `"abc123"`,
+			want:        true,
+		},
+		{
+			name:        "mismatched etag",
+			ifNoneMatch: `"different"`,
+			want:        false,
+		},
+		{
+			name:       "if-modified-since at lastModified",
+			ifModSince: now.UTC().Format(http.TimeFormat),
+			want:       true,
+		},
+		{
+			name:       "if-modified-since after lastModified",
+			ifModSince: now.Add(time.Hour).UTC().Format(http.TimeFormat),
+			want:       true,
+		},
+		{
+			name:       "if-modified-since before lastModified",
+			ifModSince: now.Add(-time.Hour).UTC().Format(http.TimeFormat),
+			want:       false,
+		},
+		{
+			name: "no conditional headers",
+			want: false,
+		},
+		{
+			name:        "etag takes precedence over stale if-modified-since",
+			ifNoneMatch: `"abc123"`,
+			ifModSince:  now.Add(-time.Hour).UTC().Format(http.TimeFormat),
+			want:        true,
+		},
+		{
+			name:       "unparseable if-modified-since is ignored",
+			ifModSince: "not-a-date",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sd/job", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.ifModSince != "" {
+				req.Header.Set("If-Modified-Since", tt.ifModSince)
+			}
+
+			if got := notModified(req, snap); got != tt.want {
+				t.Fatalf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSnapshotETagStableForSameContent(t *testing.T) {
+	a := newSnapshot([]byte(`[{"targets":["10.0.0.1:9100"]}]`))
+	b := newSnapshot([]byte(`[{"targets":["10.0.0.1:9100"]}]`))
+
+	if a.etag != b.etag {
+		t.Fatalf("expected identical content to produce identical etags, got %q and %q", a.etag, b.etag)
+	}
+
+	c := newSnapshot([]byte(`[{"targets":["10.0.0.2:9100"]}]`))
+	if a.etag == c.etag {
+		t.Fatalf("expected different content to produce different etags, both were %q", a.etag)
+	}
+}
+
+func TestServeHTTPReturns304OnMatchingETag(t *testing.T) {
+	r := &jobRefresher{}
+	r.current.Store(newSnapshot([]byte(`[{"targets":["10.0.0.1:9100"]}]`)))
+
+	etag := r.Snapshot().etag
+
+	req := httptest.NewRequest(http.MethodGet, "/sd/job", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body on 304, got %q", rec.Body.String())
+	}
+}
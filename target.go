@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cidrRegexp strips CIDR notation from IP addresses returned by Nautobot.
+var cidrRegexp = regexp.MustCompile(`/[0-9]+.*`)
+
+// newNautobotClient builds the HTTP client used to talk to Nautobot,
+// configuring client-certificate authentication when nautobot.tls is set.
+func newNautobotClient(cfg TLSConfig) (*http.Client, error) {
+	if cfg.CertFile == "" {
+		return &http.Client{Timeout: 10 * time.Second}, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading nautobot client certificate: %w", err)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}, nil
+}
+
+// fetchTargets runs a job's GraphQL query against Nautobot and renders the
+// result into Prometheus http_sd target groups, applying the job's label
+// mapping, device filter and port configuration.
+func fetchTargets(ctx context.Context, client *http.Client, nautobot NautobotConfig, job JobConfig) ([]byte, error) {
+	query, err := os.ReadFile(job.QueryFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading query file: %w", err)
+	}
+
+	// Create the payload for the GraphQL query
+	payload := map[string]string{
+		"query": string(query), // The GraphQL query as a string
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling payload: %w", err)
+	}
+
+	// Create the HTTP POST request
+	req, err := http.NewRequestWithContext(ctx, "POST", nautobot.URL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	// Set the necessary headers for the API request
+	req.Header.Set("Authorization", "Token "+nautobot.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Send the request and get the response
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check the HTTP response status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("invalid token provided: %s", string(body))
+		}
+		return nil, fmt.Errorf("unexpected HTTP status %s: %s", resp.Status, string(body))
+	}
+
+	// Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	// Decode the JSON response generically so label paths can point at any
+	// field the GraphQL query returns, not just ones we know about ahead of
+	// time.
+	var response struct {
+		Data struct {
+			Devices []map[string]interface{} `json:"devices"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+
+	// Prepare the output JSON structure. An empty slice (not nil) is
+	// important here: Prometheus's http_sd expects a JSON array even when
+	// a job's device_filter matches nothing, or Nautobot legitimately has
+	// no devices to return for this query.
+	output := []map[string]interface{}{}
+
+	// Iterate through devices and build the output structure
+	for _, device := range response.Data.Devices {
+		deviceIP, ok := deviceAddress(device, job.PreferIPv6)
+		if !ok {
+			continue
+		}
+		if !deviceMatchesFilter(device, job.DeviceFilter) {
+			continue
+		}
+
+		target := deviceIP
+		if port := devicePort(device, job); port != 0 {
+			target = joinHostPort(deviceIP, port)
+		}
+
+		// Construct the Prometheus scrape target structure
+		entry := map[string]interface{}{
+			"targets": []string{target},
+			"labels":  deviceLabels(device, job.Labels),
+		}
+		output = append(output, entry)
+	}
+
+	// Convert the output structure to JSON
+	jsonData, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling JSON: %w", err)
+	}
+
+	return jsonData, nil
+}
+
+// deviceAddress picks a device's scrape address, preferring primary_ip6
+// when preferIPv6 is set and falling back to whichever of primary_ip4 /
+// primary_ip6 is present, with CIDR notation stripped.
+func deviceAddress(device map[string]interface{}, preferIPv6 bool) (string, bool) {
+	first, second := "primary_ip4.address", "primary_ip6.address"
+	if preferIPv6 {
+		first, second = second, first
+	}
+	if v, ok := lookupString(device, first); ok {
+		return cidrRegexp.ReplaceAllString(v, ""), true
+	}
+	if v, ok := lookupString(device, second); ok {
+		return cidrRegexp.ReplaceAllString(v, ""), true
+	}
+	return "", false
+}
+
+// devicePort resolves the scrape port for a device: a per-role override
+// takes precedence over the job's default port. A result of 0 means no
+// port should be appended to the target.
+func devicePort(device map[string]interface{}, job JobConfig) int {
+	if role, ok := lookupString(device, "role.name"); ok {
+		if port, ok := job.PortOverrides[role]; ok {
+			return port
+		}
+	}
+	return job.Port
+}
+
+// joinHostPort appends a port to a scrape address, bracketing the address
+// first if it's an IPv6 literal.
+func joinHostPort(host string, port int) string {
+	if strings.Contains(host, ":") {
+		return fmt.Sprintf("[%s]:%d", host, port)
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// deviceLabels resolves a job's label mapping (label name -> GraphQL field
+// path) against a single device.
+func deviceLabels(device map[string]interface{}, mapping map[string]string) map[string]string {
+	labels := make(map[string]string, len(mapping))
+	for label, path := range mapping {
+		if value, ok := lookupString(device, path); ok {
+			labels[label] = value
+		}
+	}
+	return labels
+}
+
+// deviceMatchesFilter applies a job's device_filter, a "field=value" string
+// evaluated against the same field paths understood by lookupString. An
+// empty filter matches everything.
+func deviceMatchesFilter(device map[string]interface{}, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	field, want, ok := strings.Cut(filter, "=")
+	if !ok {
+		return true
+	}
+	got, ok := lookupString(device, field)
+	if !ok {
+		return false
+	}
+	return got == want
+}
+
+// lookupString walks a dot-separated path (e.g. "platform.name",
+// "tags.0.name", "custom_fields.snmp_community") through arbitrary
+// GraphQL-decoded JSON (maps, slices and scalars) and renders the result
+// found, if any, as a string.
+func lookupString(data interface{}, path string) (string, bool) {
+	v := data
+	for _, segment := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return "", false
+			}
+			v = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return "", false
+			}
+			v = node[idx]
+		default:
+			return "", false
+		}
+	}
+	return stringify(v)
+}
+
+// stringify renders a decoded JSON scalar as a string. Maps and slices
+// can't be flattened into a single label value, so they're rejected.
+func stringify(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case bool:
+		return strconv.FormatBool(t), true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case nil:
+		return "", false
+	default:
+		return "", false
+	}
+}
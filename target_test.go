@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeQueryFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "query.graphql")
+	if err := os.WriteFile(path, []byte(`{ devices { name primary_ip4 { address } } }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFetchTargetsEmptyResultMarshalsAsEmptyArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices string
+	}{
+		{"no devices in response", `[]`},
+		{"devices present but all filtered out", `[{"name":"switch1","role":{"name":"switch"},"primary_ip4":{"address":"192.0.2.1/24"}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data":{"devices":` + tt.devices + `}}`))
+			}))
+			defer srv.Close()
+
+			job := JobConfig{
+				Name:         "test",
+				QueryFile:    writeQueryFile(t),
+				DeviceFilter: "role.name=router",
+			}
+			nautobot := NautobotConfig{URL: srv.URL, Token: "s3cr3t"}
+
+			got, err := fetchTargets(context.Background(), srv.Client(), nautobot, job)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var decoded []map[string]interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("result didn't decode as a JSON array: %v (body: %s)", err, got)
+			}
+			if len(decoded) != 0 {
+				t.Fatalf("expected no targets, got %d", len(decoded))
+			}
+			if string(got) != "[]" {
+				t.Fatalf("expected empty result to marshal as %q, got %q", "[]", got)
+			}
+		})
+	}
+}
+
+func TestLookupString(t *testing.T) {
+	device := map[string]interface{}{
+		"name": "router1",
+		"role": map[string]interface{}{"name": "router"},
+		"primary_ip4": map[string]interface{}{
+			"address": "192.0.2.1/24",
+		},
+		"tags": []interface{}{
+			map[string]interface{}{"name": "edge"},
+		},
+		"custom_fields": map[string]interface{}{
+			"snmp_community": "public",
+			"enabled":        true,
+			"rack_unit":      float64(3),
+		},
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		ok   bool
+	}{
+		{"top level string", "name", "router1", true},
+		{"nested map", "role.name", "router", true},
+		{"array index", "tags.0.name", "edge", true},
+		{"bool stringified", "custom_fields.enabled", "true", true},
+		{"number stringified", "custom_fields.rack_unit", "3", true},
+		{"missing key", "role.missing", "", false},
+		{"missing top level", "tenant.name", "", false},
+		{"index out of range", "tags.1.name", "", false},
+		{"index into non-array", "name.0", "", false},
+		{"non-numeric index", "tags.foo.name", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lookupString(device, tt.path)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("lookupString(%q) = (%q, %v), want (%q, %v)", tt.path, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDeviceAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		device     map[string]interface{}
+		preferIPv6 bool
+		want       string
+		ok         bool
+	}{
+		{
+			name: "ipv4 only",
+			device: map[string]interface{}{
+				"primary_ip4": map[string]interface{}{"address": "192.0.2.1/24"},
+			},
+			want: "192.0.2.1",
+			ok:   true,
+		},
+		{
+			name: "ipv6 only",
+			device: map[string]interface{}{
+				"primary_ip6": map[string]interface{}{"address": "2001:db8::1/64"},
+			},
+			want: "2001:db8::1",
+			ok:   true,
+		},
+		{
+			name: "both present, ipv4 wins by default",
+			device: map[string]interface{}{
+				"primary_ip4": map[string]interface{}{"address": "192.0.2.1/24"},
+				"primary_ip6": map[string]interface{}{"address": "2001:db8::1/64"},
+			},
+			want: "192.0.2.1",
+			ok:   true,
+		},
+		{
+			name: "both present, ipv6 preferred",
+			device: map[string]interface{}{
+				"primary_ip4": map[string]interface{}{"address": "192.0.2.1/24"},
+				"primary_ip6": map[string]interface{}{"address": "2001:db8::1/64"},
+			},
+			preferIPv6: true,
+			want:       "2001:db8::1",
+			ok:         true,
+		},
+		{
+			name:   "neither present",
+			device: map[string]interface{}{},
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := deviceAddress(tt.device, tt.preferIPv6)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("deviceAddress() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		port int
+		want string
+	}{
+		{"ipv4", "192.0.2.1", 9100, "192.0.2.1:9100"},
+		{"ipv6 gets bracketed", "2001:db8::1", 9100, "[2001:db8::1]:9100"},
+		{"hostname", "device.example.com", 9116, "device.example.com:9116"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinHostPort(tt.host, tt.port); got != tt.want {
+				t.Fatalf("joinHostPort(%q, %d) = %q, want %q", tt.host, tt.port, got, tt.want)
+			}
+		})
+	}
+}